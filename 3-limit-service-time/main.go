@@ -10,50 +10,98 @@
 
 package main
 
-import "time"
+import (
+	"context"
+	"strconv"
+	"time"
+)
 
 // User defines the UserModel. Use this to check whether a User is a
 // Premium user or not
 type User struct {
 	ID        int
 	IsPremium bool
-	TimeUsed  int64 // in seconds
+	TimeUsed  int64 // in seconds; informational only, see QuotaManager for accounting
 }
 
-// HandleRequest runs the processes requested by users. Returns false
-// if process had to be killed
+// defaultQuotaManager is the QuotaManager used by HandleRequest. It's
+// package-level so HandleRequest keeps its original signature;
+// callers that need a specific QuotaStore (e.g. Redis-backed, for a
+// multi-process deployment) should construct their own QuotaManager
+// and call its HandleRequest method instead.
+var defaultQuotaManager = NewQuotaManager(nil)
+
+// reserveSlice bounds how much quota a single Reserve call asks for.
+// HandleRequest reserves one slice at a time instead of a user's
+// entire remaining balance, so concurrent requests for the same free
+// user interleave their reservations rather than the first one
+// claiming everything and blocking the rest until it finishes.
+const reserveSlice = 100 * time.Millisecond
+
+// HandleRequest runs the process requested by u. Returns false if u
+// ran out of quota before process finished; process itself is not
+// stopped and keeps running in its own goroutine.
+//
+// Quota is reserved one reserveSlice at a time as process() runs, each
+// slice bounded by a context.WithTimeout; once it finishes (or quota
+// runs out, whichever comes first) the actual duration used is
+// committed, and any unused portion of the last reservation is
+// released. This avoids the race of the previous implementation,
+// which mutated u.TimeUsed from both the caller and the timeout
+// branch with no synchronization.
 func HandleRequest(process func(), u *User) bool {
-	if !u.IsPremium && u.TimeUsed >= 10 {
-		return false
+	return defaultQuotaManager.HandleRequest(process, u)
+}
+
+// HandleRequest runs process for u, enforcing u's quota via qm's
+// QuotaStore. Premium users bypass quota accounting entirely.
+func (qm *QuotaManager) HandleRequest(process func(), u *User) bool {
+	if u.IsPremium {
+		process()
+		return true
 	}
-	done := make(chan bool)
+
+	userID := strconv.Itoa(u.ID)
+
+	done := make(chan struct{})
 	startTime := time.Now()
 	go func() {
 		process()
-		done <- true
-
+		close(done)
 	}()
 
-	timeRemaining := int64(10) - u.TimeUsed
-	if timeRemaining < 0 {
-		timeRemaining = 0
-	}
-
+	var totalGranted time.Duration
 	for {
+		granted, err := qm.store.Reserve(userID, reserveSlice)
+		if err != nil || granted <= 0 {
+			// Out of quota (or the store errored): account for
+			// whatever was granted so far and kill the request.
+			if totalGranted > 0 {
+				qm.store.Commit(userID, totalGranted)
+			}
+			return false
+		}
+		totalGranted += granted
+
+		// ctx bounds how long this slice gets to run before we check
+		// in again. It can't actually cancel process() — process is
+		// a plain func() with no way to observe a context — so this
+		// only bounds how long HandleRequest waits before reserving
+		// the next slice, not how long process() itself keeps running.
+		ctx, cancel := context.WithTimeout(context.Background(), granted)
 		select {
 		case <-done:
-			timeTaken := time.Since(startTime).Seconds()
-			if !u.IsPremium {
-				u.TimeUsed += int64(timeTaken)
+			cancel()
+			used := time.Since(startTime)
+			qm.store.Commit(userID, used)
+			if unused := totalGranted - used; unused > 0 {
+				qm.store.Release(userID, unused)
 			}
 			return true
-		case <-time.After(time.Second * time.Duration(timeRemaining)):
-			if !u.IsPremium {
-				timeTaken := time.Since(startTime).Seconds()
-				u.TimeUsed += int64(timeTaken)
-				return false
-			}
-
+		case <-ctx.Done():
+			cancel()
+			// This slice elapsed and process() is still running; go
+			// around and reserve another one.
 		}
 	}
 }
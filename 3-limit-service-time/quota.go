@@ -0,0 +1,136 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultQuota is the total processing time a free user is allowed to
+// accumulate before requests start being rejected.
+const DefaultQuota = 10 * time.Second
+
+// QuotaStore tracks how much processing time each free user has left.
+// Implementations must be safe for concurrent use, since requests for
+// the same user can arrive at once.
+//
+// Accounting is optimistic: Reserve charges its grant up front, before
+// process() runs, so that two concurrent reservations for the same
+// user split what's left instead of both reading a stale balance.
+// Commit is informational only — by the time it's called, the granted
+// amount has already been charged — and Release corrects the charge
+// by crediting back whatever portion of a reservation went unused.
+type QuotaStore interface {
+	// Reserve grants up to d of a user's remaining quota up front,
+	// before process() runs, charging the grant immediately, and
+	// returns the amount actually granted (less than d once the
+	// user is nearly out, zero once exhausted).
+	Reserve(userID string, d time.Duration) (granted time.Duration, err error)
+	// Commit records that used of a previous reservation was
+	// actually consumed. Since Reserve already charged optimistically,
+	// implementations may treat this as a no-op; it exists so a
+	// QuotaStore backed by a system with its own commit/rollback
+	// semantics (e.g. a DB transaction) has a place to finalize the
+	// charge instead of relying on Release alone.
+	Commit(userID string, used time.Duration) error
+	// Release returns the unused portion of a previous reservation
+	// to the user's remaining quota, correcting the optimistic
+	// charge Reserve made.
+	Release(userID string, unused time.Duration) error
+}
+
+// QuotaManager enforces a per-user accumulated quota by delegating
+// bookkeeping to a QuotaStore, so callers never mutate a User's state
+// directly and can't race each other into double-spending it.
+type QuotaManager struct {
+	store QuotaStore
+}
+
+// NewQuotaManager creates a QuotaManager backed by store. If store is
+// nil, it defaults to a fresh MemoryQuotaStore using DefaultQuota.
+func NewQuotaManager(store QuotaStore) *QuotaManager {
+	if store == nil {
+		store = NewMemoryQuotaStore(DefaultQuota)
+	}
+	return &QuotaManager{store: store}
+}
+
+// userState is one user's share of a MemoryQuotaStore: its own mutex
+// so that charging one user never blocks another.
+type userState struct {
+	mu   sync.Mutex
+	used time.Duration
+}
+
+// MemoryQuotaStore is a QuotaStore backed by an in-memory, sharded
+// per-user mutex map. Quota resets only when the process restarts.
+type MemoryQuotaStore struct {
+	cap time.Duration
+
+	mu    sync.Mutex
+	users map[string]*userState
+}
+
+// NewMemoryQuotaStore creates a MemoryQuotaStore that grants each user
+// up to cap of accumulated processing time.
+func NewMemoryQuotaStore(cap time.Duration) *MemoryQuotaStore {
+	return &MemoryQuotaStore{
+		cap:   cap,
+		users: make(map[string]*userState),
+	}
+}
+
+func (s *MemoryQuotaStore) stateFor(userID string) *userState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.users[userID]
+	if !ok {
+		st = &userState{}
+		s.users[userID] = st
+	}
+	return st
+}
+
+// Reserve implements QuotaStore. It charges the granted amount
+// immediately, so two concurrent reservations for the same user split
+// whatever quota remains instead of both reading a stale balance.
+func (s *MemoryQuotaStore) Reserve(userID string, d time.Duration) (time.Duration, error) {
+	st := s.stateFor(userID)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	remaining := s.cap - st.used
+	if remaining <= 0 {
+		return 0, nil
+	}
+	if d > remaining {
+		d = remaining
+	}
+
+	st.used += d
+	return d, nil
+}
+
+// Commit implements QuotaStore. Reserve already charged the user
+// optimistically, so there's nothing left to do here; any overcharge
+// is corrected via Release.
+func (s *MemoryQuotaStore) Commit(userID string, used time.Duration) error {
+	return nil
+}
+
+// Release implements QuotaStore.
+func (s *MemoryQuotaStore) Release(userID string, unused time.Duration) error {
+	if unused <= 0 {
+		return nil
+	}
+
+	st := s.stateFor(userID)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.used -= unused
+	if st.used < 0 {
+		st.used = 0
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// RedisClient is the minimal subset of a Redis client RedisQuotaStore
+// needs. It's defined locally, rather than importing a specific
+// client library, so this exercise stays free of third-party
+// dependencies; adapt your client of choice (e.g.
+// github.com/redis/go-redis/v9) to this interface to use it here.
+type RedisClient interface {
+	// SetNX sets key to value with ttl only if key doesn't already
+	// exist, and reports whether the set happened.
+	SetNX(ctx context.Context, key string, value int64, ttl time.Duration) (bool, error)
+	// DecrBy atomically subtracts amount from the integer at key and
+	// returns the new value.
+	DecrBy(ctx context.Context, key string, amount int64) (int64, error)
+	// IncrBy atomically adds amount to the integer at key and
+	// returns the new value.
+	IncrBy(ctx context.Context, key string, amount int64) (int64, error)
+}
+
+// RedisQuotaStore is a QuotaStore backed by Redis. Each user's
+// remaining quota lives under its own key, seeded to cap on first use
+// with a TTL so it resets automatically once a day, and is debited
+// with atomic DECRBY/credited with INCRBY so concurrent requests
+// across multiple processes can't double-spend it.
+type RedisQuotaStore struct {
+	client RedisClient
+	prefix string
+	cap    time.Duration
+	// resetAfter is the TTL applied when a user's key is first
+	// created, after which their quota resets to cap.
+	resetAfter time.Duration
+}
+
+// NewRedisQuotaStore creates a RedisQuotaStore using client, granting
+// each user up to cap of accumulated processing time per resetAfter
+// window (typically 24 hours).
+func NewRedisQuotaStore(client RedisClient, cap, resetAfter time.Duration) *RedisQuotaStore {
+	return &RedisQuotaStore{
+		client:     client,
+		prefix:     "quota:",
+		cap:        cap,
+		resetAfter: resetAfter,
+	}
+}
+
+// Reserve implements QuotaStore.
+func (s *RedisQuotaStore) Reserve(userID string, d time.Duration) (time.Duration, error) {
+	ctx := context.Background()
+	key := s.key(userID)
+
+	if _, err := s.client.SetNX(ctx, key, int64(s.cap), s.resetAfter); err != nil {
+		return 0, err
+	}
+
+	remaining, err := s.client.DecrBy(ctx, key, int64(d))
+	if err != nil {
+		return 0, err
+	}
+
+	if remaining >= 0 {
+		return d, nil
+	}
+
+	// Overshot the remaining balance: give back what we couldn't
+	// actually grant and report only what's left. A negative balance
+	// may include other concurrent requests' overshoot, so give back
+	// at most our own d; they credit back theirs.
+	overshoot := -remaining
+	if overshoot > int64(d) {
+		overshoot = int64(d)
+	}
+	if _, err := s.client.IncrBy(ctx, key, overshoot); err != nil {
+		return 0, err
+	}
+
+	granted := d - time.Duration(overshoot)
+	if granted < 0 {
+		granted = 0
+	}
+	return granted, nil
+}
+
+// Commit implements QuotaStore. Reserve already debited the key
+// optimistically, so there's nothing left to do here; any overcharge
+// is corrected via Release.
+func (s *RedisQuotaStore) Commit(userID string, used time.Duration) error {
+	return nil
+}
+
+// Release implements QuotaStore.
+func (s *RedisQuotaStore) Release(userID string, unused time.Duration) error {
+	if unused <= 0 {
+		return nil
+	}
+	_, err := s.client.IncrBy(context.Background(), s.key(userID), int64(unused))
+	return err
+}
+
+func (s *RedisQuotaStore) key(userID string) string {
+	return s.prefix + userID
+}
@@ -0,0 +1,82 @@
+// NOTE: this package does not currently compile with `go test` or
+// `go vet`: main.go's main() calls RunMockServer(), which (along with
+// the MockProcess type used by ../4-graceful-sigint) is part of this
+// exercise's original mock scaffolding and was already absent from
+// this tree before any of the QuotaManager/QuotaStore work landed.
+// That leaves the test and benchmark below — including the "-race
+// with 1000 concurrent requests per user" coverage the request
+// calls for — written to the same standard as the rest of the repo,
+// but unable to actually run until mockserver.go (defining
+// RunMockServer) is restored.
+package main
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestQuotaManagerConcurrentRequestsStayWithinCap drives many
+// concurrent requests for the same free user through HandleRequest
+// and checks the store never grants more than the user's cap, which
+// would indicate a double-spend.
+func TestQuotaManagerConcurrentRequestsStayWithinCap(t *testing.T) {
+	const quotaCap = 200 * time.Millisecond
+	store := NewMemoryQuotaStore(quotaCap)
+	qm := NewQuotaManager(store)
+	u := &User{ID: 1}
+
+	var successes int32
+	var wg sync.WaitGroup
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if qm.HandleRequest(func() { time.Sleep(time.Millisecond) }, u) {
+				atomic.AddInt32(&successes, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	store.mu.Lock()
+	st := store.users[strconv.Itoa(u.ID)]
+	store.mu.Unlock()
+
+	var used time.Duration
+	if st != nil {
+		st.mu.Lock()
+		used = st.used
+		st.mu.Unlock()
+	}
+
+	if used > quotaCap {
+		t.Fatalf("MemoryQuotaStore charged %v, want at most the cap of %v", used, quotaCap)
+	}
+	if successes == 0 {
+		t.Fatalf("got 0 successful requests out of 200, want the quota to allow at least some through")
+	}
+}
+
+// BenchmarkHandleRequestConcurrentSameUser drives 1000 concurrent
+// requests for the same free user through HandleRequest. Run with
+// `go test -race -bench . -run ^$` to confirm the QuotaManager/
+// QuotaStore accounting has no data races under contention.
+func BenchmarkHandleRequestConcurrentSameUser(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		qm := NewQuotaManager(nil)
+		u := &User{ID: 1}
+
+		var wg sync.WaitGroup
+		for j := 0; j < 1000; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				qm.HandleRequest(func() {}, u)
+			}()
+		}
+		wg.Wait()
+	}
+}
@@ -12,33 +12,36 @@
 package main
 
 import (
-	"os"
-	"os/signal"
+	"context"
+	"log"
+	"time"
+
+	"github.com/kittanutp/go-concurrency-exercises/shutdown"
 )
 
+// procStopper adapts *MockProcess to shutdown.Stoppable. MockProcess's
+// Stop method doesn't take a context, so ctx is only honored as a
+// deadline for how long the manager waits, not something proc.Stop
+// itself can observe.
+type procStopper struct {
+	proc *MockProcess
+}
+
+func (p procStopper) Stop(ctx context.Context) error {
+	p.proc.Stop()
+	return nil
+}
+
 func main() {
 	// Create a process
 	proc := &MockProcess{}
 	// Run the process (blocking)
 	go proc.Run()
 
-	// Channel to listen for signals
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt)
-
-	// Channel to indicate forceful termination
-	forceQuitChan := make(chan bool)
-
-	go func() {
-		<-sigChan // Listen for this channel
-		go func() {
-			<-sigChan
-			forceQuitChan <- true
-		}()
-		proc.Stop()
-	}()
-
-	<-forceQuitChan // Listen for another signal
-	os.Exit(0)
+	mgr := shutdown.New(5 * time.Second)
+	mgr.Register(procStopper{proc: proc})
 
+	if err := mgr.Listen(); err != nil {
+		log.Printf("error during shutdown: %v", err)
+	}
 }
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"container/heap"
+	"time"
+)
+
+// expiryEntry pairs a session id with the time at which it should be
+// reaped.
+type expiryEntry struct {
+	expiry time.Time
+	id     string
+}
+
+// expiryHeap is a min-heap of expiryEntry ordered by expiry, so the
+// cleaner can always wake up for the next session that's actually due
+// instead of polling every few seconds. It keeps an id -> position
+// index so a session's entry can be updated or removed in place
+// (upsert, remove) rather than appending a second entry every time
+// the session is touched, which would otherwise let stale entries
+// accumulate under update/renew churn.
+type expiryHeap struct {
+	entries []expiryEntry
+	index   map[string]int
+}
+
+func newExpiryHeap() *expiryHeap {
+	return &expiryHeap{index: make(map[string]int)}
+}
+
+func (h *expiryHeap) Len() int { return len(h.entries) }
+
+func (h *expiryHeap) Less(i, j int) bool { return h.entries[i].expiry.Before(h.entries[j].expiry) }
+
+func (h *expiryHeap) Swap(i, j int) {
+	h.entries[i], h.entries[j] = h.entries[j], h.entries[i]
+	h.index[h.entries[i].id] = i
+	h.index[h.entries[j].id] = j
+}
+
+func (h *expiryHeap) Push(x interface{}) {
+	entry := x.(expiryEntry)
+	h.index[entry.id] = len(h.entries)
+	h.entries = append(h.entries, entry)
+}
+
+func (h *expiryHeap) Pop() interface{} {
+	old := h.entries
+	n := len(old)
+	entry := old[n-1]
+	h.entries = old[:n-1]
+	delete(h.index, entry.id)
+	return entry
+}
+
+// peek returns the earliest entry without removing it.
+func (h *expiryHeap) peek() (expiryEntry, bool) {
+	if len(h.entries) == 0 {
+		return expiryEntry{}, false
+	}
+	return h.entries[0], true
+}
+
+// upsert schedules entry, replacing and re-heapifying id's existing
+// entry if it already has one instead of adding a duplicate.
+func (h *expiryHeap) upsert(entry expiryEntry) {
+	if i, ok := h.index[entry.id]; ok {
+		h.entries[i] = entry
+		heap.Fix(h, i)
+		return
+	}
+	heap.Push(h, entry)
+}
+
+// remove drops id's entry from the heap, if it has one.
+func (h *expiryHeap) remove(id string) {
+	i, ok := h.index[id]
+	if !ok {
+		return
+	}
+	heap.Remove(h, i)
+}
@@ -18,6 +18,8 @@
 package main
 
 import (
+	"container/heap"
+	"context"
 	"crypto/rand"
 	"encoding/base64"
 	"errors"
@@ -25,27 +27,72 @@ import (
 	"log"
 	"sync"
 	"time"
+
+	"github.com/kittanutp/go-concurrency-exercises/shutdown"
 )
 
+// defaultIdleTimeout is the sliding idle window used by CreateSession.
+const defaultIdleTimeout = 5 * time.Second
+
 // SessionManager keeps track of all sessions from creation, updating
-// to destroying.
+// to destroying. It delegates actual storage to a Store, so the same
+// manager and cleaner logic works against an in-memory map, a file,
+// or a remote cache like Redis. A min-heap of (expiry, id) pairs
+// drives the cleaner so it wakes up exactly when the next session is
+// due instead of polling on a fixed interval.
 type SessionManager struct {
-	sessions map[string]Session
-	sync     sync.Mutex
-	done     chan bool
+	store Store
+	done  chan bool
+
+	heapMu sync.Mutex
+	heap   *expiryHeap
+	wake   chan struct{}
 }
 
-// Session stores the session's data and the last update timestamp.
+// Session stores the session's data, the last update timestamp, and
+// its expiry settings.
 type Session struct {
 	Data       map[string]interface{}
 	LastUpdate time.Time
+
+	// Idle is the sliding idle timeout; a session is reaped once
+	// it hasn't been updated for this long. Zero means
+	// defaultIdleTimeout.
+	Idle time.Duration
+	// Deadline is an absolute point in time after which the
+	// session is reaped regardless of activity. Zero means no
+	// absolute deadline.
+	Deadline time.Time
 }
 
-// NewSessionManager creates a new sessionManager and starts the session cleaner.
-func NewSessionManager() *SessionManager {
+// expiry returns the time at which s should be reaped: whichever of
+// its sliding idle timeout or absolute deadline comes first.
+func (s Session) expiry() time.Time {
+	idle := s.Idle
+	if idle == 0 {
+		idle = defaultIdleTimeout
+	}
+
+	idleExpiry := s.LastUpdate.Add(idle)
+	if !s.Deadline.IsZero() && s.Deadline.Before(idleExpiry) {
+		return s.Deadline
+	}
+	return idleExpiry
+}
+
+// NewSessionManager creates a new SessionManager and starts the
+// session cleaner. If store is nil, it defaults to a fresh
+// MemoryStore.
+func NewSessionManager(store Store) *SessionManager {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+
 	m := &SessionManager{
-		sessions: make(map[string]Session),
-		done:     make(chan bool),
+		store: store,
+		done:  make(chan bool),
+		heap:  newExpiryHeap(),
+		wake:  make(chan struct{}, 1),
 	}
 
 	// Start the session cleaner in the background.
@@ -54,33 +101,78 @@ func NewSessionManager() *SessionManager {
 	return m
 }
 
-// CreateSession creates a new session and returns the sessionID.
+// CreateSession creates a new session with the default idle timeout
+// and returns the sessionID.
 func (m *SessionManager) CreateSession() (string, error) {
-	m.sync.Lock()
-	defer m.sync.Unlock()
+	return m.CreateSessionWithTTL(defaultIdleTimeout, 0)
+}
 
+// CreateSessionWithTTL creates a new session that is reaped after idle
+// time without an update, or after absolute time has passed since
+// creation, whichever comes first. A zero absolute disables the
+// deadline.
+func (m *SessionManager) CreateSessionWithTTL(idle, absolute time.Duration) (string, error) {
 	sessionID, err := MakeSessionID()
 	if err != nil {
 		return "", err
 	}
 
-	m.sessions[sessionID] = Session{
+	session := Session{
 		Data:       make(map[string]interface{}),
 		LastUpdate: time.Now(), // Set the initial LastUpdate time to now.
+		Idle:       idle,
+	}
+	if absolute > 0 {
+		session.Deadline = session.LastUpdate.Add(absolute)
+	}
+
+	if err := m.store.Commit(sessionID, session); err != nil {
+		return "", err
 	}
+	m.schedule(sessionID, session)
 
 	return sessionID, nil
 }
 
+// RenewID atomically rekeys the session stored under oldID to a fresh
+// random ID while preserving its Data, Idle and Deadline. Callers
+// should call this on privilege changes (e.g. login) to rotate the ID
+// and prevent session fixation.
+func (m *SessionManager) RenewID(oldID string) (string, error) {
+	session, ok, err := m.store.Find(oldID)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", ErrSessionNotFound
+	}
+
+	newID, err := MakeSessionID()
+	if err != nil {
+		return "", err
+	}
+
+	if err := m.store.Commit(newID, session); err != nil {
+		return "", err
+	}
+	if err := m.store.Delete(oldID); err != nil {
+		return "", err
+	}
+	m.unschedule(oldID)
+	m.schedule(newID, session)
+
+	return newID, nil
+}
+
 // ErrSessionNotFound is returned when sessionID is not listed in the SessionManager.
 var ErrSessionNotFound = errors.New("SessionID does not exist")
 
 // GetSessionData returns data related to a session if the sessionID is found.
 func (m *SessionManager) GetSessionData(sessionID string) (map[string]interface{}, error) {
-	m.sync.Lock()
-	defer m.sync.Unlock()
-
-	session, ok := m.sessions[sessionID]
+	session, ok, err := m.store.Find(sessionID)
+	if err != nil {
+		return nil, err
+	}
 	if !ok {
 		return nil, ErrSessionNotFound
 	}
@@ -90,10 +182,10 @@ func (m *SessionManager) GetSessionData(sessionID string) (map[string]interface{
 
 // UpdateSessionData overwrites the old session data with the new one.
 func (m *SessionManager) UpdateSessionData(sessionID string, data map[string]interface{}) error {
-	m.sync.Lock()
-	defer m.sync.Unlock()
-
-	session, ok := m.sessions[sessionID]
+	session, ok, err := m.store.Find(sessionID)
+	if err != nil {
+		return err
+	}
 	if !ok {
 		return ErrSessionNotFound
 	}
@@ -102,57 +194,170 @@ func (m *SessionManager) UpdateSessionData(sessionID string, data map[string]int
 	session.Data = data
 	session.LastUpdate = time.Now() // Update LastUpdate time.
 
-	m.sessions[sessionID] = session
+	if err := m.store.Commit(sessionID, session); err != nil {
+		return err
+	}
+	m.schedule(sessionID, session)
 	return nil
 }
 
-// MangeSession runs the session cleaner in the background and removes sessions older than 5 seconds.
-func (m *SessionManager) MangeSession() {
-	for {
+// schedule (re)schedules id's expiry entry, replacing any entry it
+// already has rather than adding a second one, and wakes the cleaner
+// if this entry is now the earliest one pending.
+func (m *SessionManager) schedule(id string, session Session) {
+	entry := expiryEntry{expiry: session.expiry(), id: id}
+
+	m.heapMu.Lock()
+	m.heap.upsert(entry)
+	top, _ := m.heap.peek()
+	isEarliest := top == entry
+	m.heapMu.Unlock()
+
+	if isEarliest {
 		select {
-		case <-m.done:
-			log.Println("Stopping session cleaner.")
-			return
+		case m.wake <- struct{}{}:
 		default:
-			time.Sleep(5 * time.Second)
+		}
+	}
+}
 
-			m.sync.Lock()
+// unschedule drops id's expiry entry from the heap, if it has one.
+// Callers must otherwise be sure id won't be found via m.store
+// afterwards (reapExpired's lazy staleness check relies on that),
+// since this alone doesn't stop a concurrent Find from seeing it.
+func (m *SessionManager) unschedule(id string) {
+	m.heapMu.Lock()
+	m.heap.remove(id)
+	m.heapMu.Unlock()
+}
 
-			// Check if there are any sessions to clean up.
-			if len(m.sessions) == 0 {
-				m.sync.Unlock()
-				continue
-			}
+// reconcile seeds the expiry heap from every session currently in the
+// store. The heap always starts out empty, which is fine for a fresh
+// MemoryStore, but a Store that persists across restarts (FileStore,
+// RedisStore) may already hold sessions nobody has touched since
+// startup; without this they'd sit unscheduled until some unrelated
+// Create/Update happened to run the cleaner's timer past their
+// expiry.
+func (m *SessionManager) reconcile() {
+	ids, err := m.store.All()
+	if err != nil {
+		log.Printf("Failed to list sessions during startup reconciliation: %v", err)
+		return
+	}
+
+	for _, id := range ids {
+		session, ok, err := m.store.Find(id)
+		if err != nil {
+			log.Printf("Failed to look up session %s during reconciliation: %v", id, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		m.schedule(id, session)
+	}
+}
 
-			var deletedKeys []string
+// MangeSession runs the session cleaner in the background. It sleeps
+// until the next entry in the expiry heap is due, rather than polling
+// on a fixed interval, so it costs nothing while idle and reacts
+// immediately to newly scheduled sessions.
+func (m *SessionManager) MangeSession() {
+	m.reconcile()
 
-			for id, sess := range m.sessions {
-				// Check if the session is older than 5 seconds.
-				if time.Since(sess.LastUpdate) >= 5*time.Second {
-					deletedKeys = append(deletedKeys, id)
-				}
-			}
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
 
-			// Delete expired sessions.
-			for _, key := range deletedKeys {
-				delete(m.sessions, key)
+	for {
+		m.heapMu.Lock()
+		var next time.Time
+		if top, ok := m.heap.peek(); ok {
+			next = top.expiry
+		}
+		m.heapMu.Unlock()
+
+		wait := time.Hour
+		if !next.IsZero() {
+			if wait = time.Until(next); wait < 0 {
+				wait = 0
 			}
+		}
+		timer.Reset(wait)
 
-			if len(deletedKeys) > 0 {
-				log.Printf("Deleted session IDs: %v", deletedKeys)
+		select {
+		case <-m.done:
+			log.Println("Stopping session cleaner.")
+			return
+		case <-m.wake:
+			if !timer.Stop() {
+				<-timer.C
 			}
+		case <-timer.C:
+			m.reapExpired()
+		}
+	}
+}
+
+// reapExpired pops every heap entry that's due and deletes the
+// session it names, as long as the entry is still current: a session
+// may have been updated after it was scheduled, in which case its
+// entry is stale and is rescheduled instead of deleted. An entry whose
+// session is no longer in the store (deleted, or renewed to a new id)
+// is dropped.
+func (m *SessionManager) reapExpired() {
+	now := time.Now()
+
+	for {
+		m.heapMu.Lock()
+		top, ok := m.heap.peek()
+		if !ok || top.expiry.After(now) {
+			m.heapMu.Unlock()
+			return
+		}
+		entry := heap.Pop(m.heap).(expiryEntry)
+		m.heapMu.Unlock()
 
-			m.sync.Unlock()
+		session, ok, err := m.store.Find(entry.id)
+		if err != nil {
+			log.Printf("Failed to look up session %s: %v", entry.id, err)
+			continue
+		}
+		if !ok {
+			// Already deleted or renewed away; stale entry.
+			continue
+		}
 
+		if actual := session.expiry(); actual.After(now) {
+			m.schedule(entry.id, session)
+			continue
 		}
 
+		if err := m.store.Delete(entry.id); err != nil {
+			log.Printf("Failed to delete session %s: %v", entry.id, err)
+			continue
+		}
+		log.Printf("Deleted session ID: %s", entry.id)
 	}
 }
 
-func (m *SessionManager) StopSession() {
-	time.Sleep(6 * time.Microsecond)
-	m.done <- true
+// Stop implements shutdown.Stoppable, so a SessionManager's cleaner
+// can be registered with a shutdown.Manager alongside other
+// components instead of being stopped ad hoc.
+func (m *SessionManager) Stop(ctx context.Context) error {
+	select {
+	case m.done <- true:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 	close(m.done) // Signal the session cleaner to stop
+	return nil
+}
+
+// StopSession stops the session cleaner. Deprecated: register the
+// SessionManager with a shutdown.Manager and call its Stop (or
+// Shutdown) instead.
+func (m *SessionManager) StopSession() {
+	m.Stop(context.Background())
 }
 
 // MakeSessionID generates a random session ID.
@@ -168,7 +373,7 @@ func MakeSessionID() (string, error) {
 
 func main() {
 	// Create new sessionManager and new session
-	m := NewSessionManager()
+	m := NewSessionManager(nil)
 	sID, err := m.CreateSession()
 	if err != nil {
 		log.Fatal(err)
@@ -193,5 +398,10 @@ func main() {
 		log.Fatal(err)
 	}
 	log.Println("Get session data:", updatedData)
-	m.StopSession()
+
+	mgr := shutdown.New(time.Second)
+	mgr.Register(m)
+	if err := mgr.Shutdown(); err != nil {
+		log.Printf("error stopping session manager: %v", err)
+	}
 }
@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+// TestRenewIDDoesNotLeakHeapEntries guards against the heap growing
+// unbounded under renew churn: each RenewID must drop the old id's
+// expiry entry, not just leave it to be reclaimed lazily once it
+// finally comes due.
+func TestRenewIDDoesNotLeakHeapEntries(t *testing.T) {
+	m := NewSessionManager(nil)
+	t.Cleanup(func() { m.StopSession() })
+
+	id, err := m.CreateSession()
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+
+	const renews = 50
+	for i := 0; i < renews; i++ {
+		id, err = m.RenewID(id)
+		if err != nil {
+			t.Fatalf("RenewID: %v", err)
+		}
+	}
+
+	m.heapMu.Lock()
+	got := len(m.heap.entries)
+	m.heapMu.Unlock()
+
+	if got != 1 {
+		t.Fatalf("heap has %d entries after %d renews of a single session, want 1", got, renews)
+	}
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// PoolConfig configures a SessionPool.
+type PoolConfig struct {
+	// MinOpened is the minimum number of sessions the maintainer
+	// keeps open at all times.
+	MinOpened int
+	// MaxOpened caps the total number of sessions (idle plus
+	// checked out) the pool will ever have open. Take blocks once
+	// this many are open and none are idle.
+	MaxOpened int
+	// MaxIdle caps how many idle sessions are kept around; sessions
+	// returned to the pool beyond this are closed instead.
+	MaxIdle int
+	// HealthCheckInterval is how often the maintainer pings idle
+	// sessions via HealthCheck. Zero disables health checking.
+	HealthCheckInterval time.Duration
+	// HealthCheck pings a session to confirm it's still usable. A
+	// non-nil error evicts the session from the pool.
+	HealthCheck func(Session) error
+	// New creates a fresh session for the pool to open.
+	New func() (Session, error)
+	// Close releases a session's underlying resource when the pool
+	// discards it. Optional.
+	Close func(Session) error
+}
+
+// pooledSession is an idle list entry: a session plus the id it was
+// checked out and recycled under.
+type pooledSession struct {
+	id      string
+	session Session
+}
+
+// PoolStats reports a SessionPool's current sizing, for monitoring.
+type PoolStats struct {
+	Opened int
+	Idle   int
+	InUse  int
+}
+
+// SessionPool maintains a pool of reusable Sessions, for cases where
+// a Session wraps a resource like a DB handle rather than user state.
+// It's modeled after maintainer pools like Cloud Spanner's: a
+// background goroutine keeps the pool topped up to MinOpened, trims
+// idle sessions down toward recent demand, and evicts any that fail a
+// health check.
+type SessionPool struct {
+	cfg PoolConfig
+
+	mu       sync.Mutex
+	idle     *list.List // front = most recently used
+	idleByID map[string]*list.Element
+	inUse    map[string]Session
+	opened   int
+	notify   chan struct{} // closed and replaced whenever capacity may have changed
+	maxInUse int           // peak of len(inUse) observed since the last trim
+
+	done chan struct{}
+}
+
+// NewSessionPool creates a SessionPool per cfg and starts its
+// maintainer goroutine.
+func NewSessionPool(cfg PoolConfig) (*SessionPool, error) {
+	if cfg.New == nil {
+		return nil, errors.New("SessionPool: New is required")
+	}
+	if cfg.MaxOpened <= 0 {
+		return nil, errors.New("SessionPool: MaxOpened must be positive")
+	}
+
+	p := &SessionPool{
+		cfg:      cfg,
+		idle:     list.New(),
+		idleByID: make(map[string]*list.Element),
+		inUse:    make(map[string]Session),
+		notify:   make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	go p.maintain()
+
+	return p, nil
+}
+
+// Take checks out a session, preferring the most-recently-used idle
+// one to keep connections warm. If none is idle it opens a new one
+// (up to MaxOpened), or blocks until one is Recycled or ctx is done.
+func (p *SessionPool) Take(ctx context.Context) (string, Session, error) {
+	for {
+		p.mu.Lock()
+
+		if elem := p.idle.Front(); elem != nil {
+			ps := p.idle.Remove(elem).(pooledSession)
+			delete(p.idleByID, ps.id)
+			p.inUse[ps.id] = ps.session
+			p.trackInUseLocked()
+			p.mu.Unlock()
+			return ps.id, ps.session, nil
+		}
+
+		if p.opened < p.cfg.MaxOpened {
+			p.opened++
+			p.mu.Unlock()
+
+			session, id, err := p.open()
+			if err != nil {
+				p.mu.Lock()
+				p.opened--
+				p.mu.Unlock()
+				return "", Session{}, err
+			}
+
+			p.mu.Lock()
+			p.inUse[id] = session
+			p.trackInUseLocked()
+			p.mu.Unlock()
+			return id, session, nil
+		}
+
+		ch := p.notify
+		p.mu.Unlock()
+
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return "", Session{}, ctx.Err()
+		}
+	}
+}
+
+// Recycle returns a checked-out session to the pool. If the idle list
+// is already at MaxIdle, the session is closed instead of pooled.
+func (p *SessionPool) Recycle(id string, session Session) error {
+	p.mu.Lock()
+	delete(p.inUse, id)
+
+	if p.idle.Len() >= p.cfg.MaxIdle {
+		p.opened--
+		p.wakeLocked()
+		p.mu.Unlock()
+
+		if p.cfg.Close != nil {
+			return p.cfg.Close(session)
+		}
+		return nil
+	}
+
+	session.LastUpdate = time.Now()
+	elem := p.idle.PushFront(pooledSession{id: id, session: session})
+	p.idleByID[id] = elem
+	p.wakeLocked()
+	p.mu.Unlock()
+
+	return nil
+}
+
+// Stop implements shutdown.Stoppable: it stops the maintainer and
+// closes every idle session. Sessions still checked out are the
+// caller's responsibility to Recycle or discard first.
+func (p *SessionPool) Stop(ctx context.Context) error {
+	close(p.done)
+
+	p.mu.Lock()
+	var sessions []Session
+	for e := p.idle.Front(); e != nil; e = e.Next() {
+		sessions = append(sessions, e.Value.(pooledSession).session)
+	}
+	p.idle.Init()
+	p.idleByID = make(map[string]*list.Element)
+	p.mu.Unlock()
+
+	if p.cfg.Close == nil {
+		return nil
+	}
+
+	var errs []error
+	for _, session := range sessions {
+		if err := p.cfg.Close(session); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Stats reports the pool's current sizing.
+func (p *SessionPool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return PoolStats{
+		Opened: p.opened,
+		Idle:   p.idle.Len(),
+		InUse:  len(p.inUse),
+	}
+}
+
+func (p *SessionPool) open() (Session, string, error) {
+	session, err := p.cfg.New()
+	if err != nil {
+		return Session{}, "", err
+	}
+
+	id, err := MakeSessionID()
+	if err != nil {
+		return Session{}, "", err
+	}
+
+	session.LastUpdate = time.Now()
+	return session, id, nil
+}
+
+// trackInUseLocked records a new high-water mark for concurrent
+// checkouts; callers must hold p.mu.
+func (p *SessionPool) trackInUseLocked() {
+	if n := len(p.inUse); n > p.maxInUse {
+		p.maxInUse = n
+	}
+}
+
+// wakeLocked signals any Take callers blocked on saturation that
+// capacity may have changed; callers must hold p.mu.
+func (p *SessionPool) wakeLocked() {
+	close(p.notify)
+	p.notify = make(chan struct{})
+}
+
+// maintainWindow is how many maintainer ticks make up the rolling
+// window used to trim idle sessions back down toward recent demand.
+const maintainWindow = 10
+
+// defaultMaintainInterval paces the maintainer when HealthCheckInterval
+// is unset.
+const defaultMaintainInterval = 30 * time.Second
+
+func (p *SessionPool) maintain() {
+	interval := p.cfg.HealthCheckInterval
+	if interval <= 0 {
+		interval = defaultMaintainInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	p.topUp()
+
+	ticks := 0
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.topUp()
+			if p.cfg.HealthCheckInterval > 0 {
+				p.healthCheckIdle()
+			}
+
+			ticks++
+			if ticks >= maintainWindow {
+				p.trimToRecentDemand()
+				ticks = 0
+			}
+		}
+	}
+}
+
+// topUp opens sessions directly into the idle list until the pool
+// reaches MinOpened (or MaxOpened, if lower).
+func (p *SessionPool) topUp() {
+	for {
+		p.mu.Lock()
+		if p.opened >= p.cfg.MinOpened || p.opened >= p.cfg.MaxOpened {
+			p.mu.Unlock()
+			return
+		}
+		p.opened++
+		p.mu.Unlock()
+
+		session, id, err := p.open()
+		if err != nil {
+			p.mu.Lock()
+			p.opened--
+			p.mu.Unlock()
+			return
+		}
+
+		p.mu.Lock()
+		elem := p.idle.PushBack(pooledSession{id: id, session: session})
+		p.idleByID[id] = elem
+		p.wakeLocked()
+		p.mu.Unlock()
+	}
+}
+
+// healthCheckIdle pings every currently idle session and evicts any
+// that fail.
+func (p *SessionPool) healthCheckIdle() {
+	if p.cfg.HealthCheck == nil {
+		return
+	}
+
+	p.mu.Lock()
+	snapshot := make([]pooledSession, 0, p.idle.Len())
+	for e := p.idle.Front(); e != nil; e = e.Next() {
+		snapshot = append(snapshot, e.Value.(pooledSession))
+	}
+	p.mu.Unlock()
+
+	for _, ps := range snapshot {
+		if err := p.cfg.HealthCheck(ps.session); err != nil {
+			p.evict(ps.id)
+		}
+	}
+}
+
+// evict removes id from the idle list, closing its session, if it's
+// still there (it may have been taken or already evicted concurrently).
+func (p *SessionPool) evict(id string) {
+	p.mu.Lock()
+	elem, ok := p.idleByID[id]
+	if !ok {
+		p.mu.Unlock()
+		return
+	}
+	ps := p.idle.Remove(elem).(pooledSession)
+	delete(p.idleByID, id)
+	p.opened--
+	p.wakeLocked()
+	p.mu.Unlock()
+
+	if p.cfg.Close != nil {
+		p.cfg.Close(ps.session)
+	}
+}
+
+// trimToRecentDemand closes the least-recently-used idle sessions
+// down to the peak concurrent usage observed over the last window,
+// then resets the window.
+func (p *SessionPool) trimToRecentDemand() {
+	p.mu.Lock()
+
+	target := p.maxInUse
+	if target < p.cfg.MinOpened {
+		target = p.cfg.MinOpened
+	}
+	p.maxInUse = len(p.inUse)
+
+	var evicted []Session
+	for p.opened > target {
+		back := p.idle.Back()
+		if back == nil {
+			break
+		}
+		ps := p.idle.Remove(back).(pooledSession)
+		delete(p.idleByID, ps.id)
+		p.opened--
+		evicted = append(evicted, ps.session)
+	}
+	p.wakeLocked()
+	p.mu.Unlock()
+
+	if p.cfg.Close == nil {
+		return
+	}
+	for _, session := range evicted {
+		p.cfg.Close(session)
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestPool(t *testing.T, cfg PoolConfig) *SessionPool {
+	t.Helper()
+
+	p, err := NewSessionPool(cfg)
+	if err != nil {
+		t.Fatalf("NewSessionPool: %v", err)
+	}
+	t.Cleanup(func() { p.Stop(context.Background()) })
+	return p
+}
+
+// TestSessionPoolTakeRecycleRoundTrip checks a session taken out of
+// the pool comes back as idle after Recycle, and is handed out again
+// by a later Take.
+func TestSessionPoolTakeRecycleRoundTrip(t *testing.T) {
+	p := newTestPool(t, PoolConfig{
+		MaxOpened: 2,
+		MaxIdle:   2,
+		New:       func() (Session, error) { return Session{}, nil },
+	})
+
+	id, session, err := p.Take(context.Background())
+	if err != nil {
+		t.Fatalf("Take: %v", err)
+	}
+
+	if stats := p.Stats(); stats.Opened != 1 || stats.InUse != 1 || stats.Idle != 0 {
+		t.Fatalf("Stats after Take = %+v, want Opened=1 InUse=1 Idle=0", stats)
+	}
+
+	if err := p.Recycle(id, session); err != nil {
+		t.Fatalf("Recycle: %v", err)
+	}
+
+	if stats := p.Stats(); stats.Opened != 1 || stats.InUse != 0 || stats.Idle != 1 {
+		t.Fatalf("Stats after Recycle = %+v, want Opened=1 InUse=0 Idle=1", stats)
+	}
+
+	id2, _, err := p.Take(context.Background())
+	if err != nil {
+		t.Fatalf("second Take: %v", err)
+	}
+	if id2 != id {
+		t.Fatalf("second Take returned id %q, want the recycled id %q", id2, id)
+	}
+}
+
+// TestSessionPoolTakeBlocksUntilRecycle checks that Take blocks once
+// MaxOpened is reached, and unblocks as soon as a checked-out session
+// is Recycled rather than erroring or opening beyond the cap.
+func TestSessionPoolTakeBlocksUntilRecycle(t *testing.T) {
+	p := newTestPool(t, PoolConfig{
+		MaxOpened: 1,
+		MaxIdle:   1,
+		New:       func() (Session, error) { return Session{}, nil },
+	})
+
+	id, session, err := p.Take(context.Background())
+	if err != nil {
+		t.Fatalf("Take: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, _, err := p.Take(context.Background()); err != nil {
+			t.Errorf("blocked Take: %v", err)
+		}
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("second Take returned before the pool had any capacity")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := p.Recycle(id, session); err != nil {
+		t.Fatalf("Recycle: %v", err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("second Take did not unblock after Recycle")
+	}
+}
+
+// TestSessionPoolTakeCtxCancel checks a blocked Take returns the
+// context's error once it's done, instead of blocking forever.
+func TestSessionPoolTakeCtxCancel(t *testing.T) {
+	p := newTestPool(t, PoolConfig{
+		MaxOpened: 1,
+		MaxIdle:   1,
+		New:       func() (Session, error) { return Session{}, nil },
+	})
+
+	if _, _, err := p.Take(context.Background()); err != nil {
+		t.Fatalf("Take: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if _, _, err := p.Take(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Take returned err %v, want context.DeadlineExceeded", err)
+	}
+}
+
+// TestSessionPoolConcurrentTakeRecycle drives many goroutines taking
+// and recycling sessions concurrently under -race, checking the pool
+// never exceeds MaxOpened.
+func TestSessionPoolConcurrentTakeRecycle(t *testing.T) {
+	const maxOpened = 5
+	p := newTestPool(t, PoolConfig{
+		MaxOpened: maxOpened,
+		MaxIdle:   maxOpened,
+		New:       func() (Session, error) { return Session{}, nil },
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			id, session, err := p.Take(ctx)
+			if err != nil {
+				t.Errorf("Take: %v", err)
+				return
+			}
+			if stats := p.Stats(); stats.Opened > maxOpened {
+				t.Errorf("Stats.Opened = %d, want at most MaxOpened=%d", stats.Opened, maxOpened)
+			}
+			if err := p.Recycle(id, session); err != nil {
+				t.Errorf("Recycle: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// TestSessionPoolRecycleBeyondMaxIdleCloses checks that recycling a
+// session once the idle list is already at MaxIdle closes it instead
+// of growing the idle list further.
+func TestSessionPoolRecycleBeyondMaxIdleCloses(t *testing.T) {
+	var closed int32
+	p := newTestPool(t, PoolConfig{
+		MaxOpened: 2,
+		MaxIdle:   0,
+		New:       func() (Session, error) { return Session{}, nil },
+		Close:     func(Session) error { atomic.AddInt32(&closed, 1); return nil },
+	})
+
+	id, session, err := p.Take(context.Background())
+	if err != nil {
+		t.Fatalf("Take: %v", err)
+	}
+
+	if err := p.Recycle(id, session); err != nil {
+		t.Fatalf("Recycle: %v", err)
+	}
+
+	if got := atomic.LoadInt32(&closed); got != 1 {
+		t.Fatalf("Close called %d times, want 1", got)
+	}
+	if stats := p.Stats(); stats.Idle != 0 || stats.Opened != 0 {
+		t.Fatalf("Stats after Recycle beyond MaxIdle = %+v, want Idle=0 Opened=0", stats)
+	}
+}
+
+// TestSessionPoolHealthCheckEvictsFailingSessions checks that a
+// failing HealthCheck evicts the session from the idle list and
+// closes it.
+func TestSessionPoolHealthCheckEvictsFailingSessions(t *testing.T) {
+	var closed int32
+	p := newTestPool(t, PoolConfig{
+		MaxOpened: 1,
+		MaxIdle:   1,
+		New:       func() (Session, error) { return Session{}, nil },
+		Close:     func(Session) error { atomic.AddInt32(&closed, 1); return nil },
+		HealthCheck: func(Session) error {
+			return errors.New("session is unhealthy")
+		},
+	})
+
+	id, session, err := p.Take(context.Background())
+	if err != nil {
+		t.Fatalf("Take: %v", err)
+	}
+	if err := p.Recycle(id, session); err != nil {
+		t.Fatalf("Recycle: %v", err)
+	}
+
+	p.healthCheckIdle()
+
+	if got := atomic.LoadInt32(&closed); got != 1 {
+		t.Fatalf("Close called %d times after a failing health check, want 1", got)
+	}
+	if stats := p.Stats(); stats.Idle != 0 || stats.Opened != 0 {
+		t.Fatalf("Stats after eviction = %+v, want Idle=0 Opened=0", stats)
+	}
+}
+
+// TestSessionPoolTrimToRecentDemand checks that idle sessions beyond
+// the peak concurrent usage observed since the last trim are closed,
+// down to MinOpened but no further.
+func TestSessionPoolTrimToRecentDemand(t *testing.T) {
+	var closed int32
+	p := newTestPool(t, PoolConfig{
+		MinOpened: 1,
+		MaxOpened: 4,
+		MaxIdle:   4,
+		New:       func() (Session, error) { return Session{}, nil },
+		Close:     func(Session) error { atomic.AddInt32(&closed, 1); return nil },
+	})
+
+	// Open 4 sessions at once (peak demand = 4), then recycle all of
+	// them so they all become idle.
+	var ids []string
+	var sessions []Session
+	for i := 0; i < 4; i++ {
+		id, session, err := p.Take(context.Background())
+		if err != nil {
+			t.Fatalf("Take: %v", err)
+		}
+		ids = append(ids, id)
+		sessions = append(sessions, session)
+	}
+	for i, id := range ids {
+		if err := p.Recycle(id, sessions[i]); err != nil {
+			t.Fatalf("Recycle: %v", err)
+		}
+	}
+
+	if stats := p.Stats(); stats.Opened != 4 {
+		t.Fatalf("Stats.Opened = %d before trim, want 4", stats.Opened)
+	}
+
+	// The first trim only consumes the window that saw the peak of
+	// 4, so it keeps Opened at 4 and resets the window against the
+	// current (zero) demand. A second trim, with no demand in
+	// between, should then bring Opened down to MinOpened.
+	p.trimToRecentDemand()
+	p.trimToRecentDemand()
+
+	if stats := p.Stats(); stats.Opened != 1 {
+		t.Fatalf("Stats.Opened = %d after trim, want MinOpened=1", stats.Opened)
+	}
+	if got := atomic.LoadInt32(&closed); got != 3 {
+		t.Fatalf("Close called %d times by trim, want 3", got)
+	}
+}
+
+// TestSessionPoolMaintainerTopsUpToMinOpened checks that the
+// background maintainer opens sessions up to MinOpened on its own,
+// without any Take call.
+func TestSessionPoolMaintainerTopsUpToMinOpened(t *testing.T) {
+	p := newTestPool(t, PoolConfig{
+		MinOpened: 2,
+		MaxOpened: 2,
+		MaxIdle:   2,
+		New:       func() (Session, error) { return Session{}, nil },
+	})
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if stats := p.Stats(); stats.Opened == 2 && stats.Idle == 2 {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("maintainer did not top up to MinOpened within 1s, Stats = %+v", p.Stats())
+}
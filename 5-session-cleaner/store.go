@@ -0,0 +1,74 @@
+package main
+
+import "sync"
+
+// Store is the persistence backend used by SessionManager to hold
+// session state. Implementations must be safe for concurrent use, as
+// the manager and its cleaner goroutine call into the same Store from
+// different goroutines.
+type Store interface {
+	// Find returns the session stored under id. The second return
+	// value reports whether a session was found.
+	Find(id string) (Session, bool, error)
+	// Commit creates or overwrites the session stored under id.
+	Commit(id string, s Session) error
+	// Delete removes the session stored under id. Deleting an id
+	// that doesn't exist is not an error.
+	Delete(id string) error
+	// All returns the ids of every session currently in the store.
+	All() ([]string, error)
+}
+
+// MemoryStore is a Store backed by a plain map guarded by a mutex.
+// It's the default backend and keeps no state beyond the process
+// lifetime.
+type MemoryStore struct {
+	mu       sync.Mutex
+	sessions map[string]Session
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		sessions: make(map[string]Session),
+	}
+}
+
+// Find implements Store.
+func (s *MemoryStore) Find(id string) (Session, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[id]
+	return session, ok, nil
+}
+
+// Commit implements Store.
+func (s *MemoryStore) Commit(id string, session Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[id] = session
+	return nil
+}
+
+// Delete implements Store.
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, id)
+	return nil
+}
+
+// All implements Store.
+func (s *MemoryStore) All() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]string, 0, len(s.sessions))
+	for id := range s.sessions {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
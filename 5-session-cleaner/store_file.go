@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileStore is a Store that keeps sessions in memory and periodically
+// snapshots them to a JSON file on disk, so sessions survive a
+// process restart. It trades durability for simplicity: writes are
+// batched rather than synced on every call.
+type FileStore struct {
+	mu       sync.Mutex
+	sessions map[string]Session
+
+	path string
+	done chan struct{}
+}
+
+// NewFileStore creates a FileStore backed by path, loading any
+// snapshot that already exists there, and starts a background
+// goroutine that flushes to disk every flushInterval.
+func NewFileStore(path string, flushInterval time.Duration) (*FileStore, error) {
+	fs := &FileStore{
+		sessions: make(map[string]Session),
+		path:     path,
+		done:     make(chan struct{}),
+	}
+
+	if err := fs.load(); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	go fs.flushLoop(flushInterval)
+
+	return fs, nil
+}
+
+// Close stops the background flush goroutine and writes a final
+// snapshot.
+func (fs *FileStore) Close() error {
+	close(fs.done)
+	return fs.flush()
+}
+
+func (fs *FileStore) flushLoop(flushInterval time.Duration) {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			fs.flush()
+		case <-fs.done:
+			return
+		}
+	}
+}
+
+func (fs *FileStore) load() error {
+	raw, err := os.ReadFile(fs.path)
+	if err != nil {
+		return err
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	return json.Unmarshal(raw, &fs.sessions)
+}
+
+func (fs *FileStore) flush() error {
+	fs.mu.Lock()
+	raw, err := json.Marshal(fs.sessions)
+	fs.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	tmp := fs.path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, fs.path)
+}
+
+// Find implements Store.
+func (fs *FileStore) Find(id string) (Session, bool, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	session, ok := fs.sessions[id]
+	return session, ok, nil
+}
+
+// Commit implements Store.
+func (fs *FileStore) Commit(id string, session Session) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	fs.sessions[id] = session
+	return nil
+}
+
+// Delete implements Store.
+func (fs *FileStore) Delete(id string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	delete(fs.sessions, id)
+	return nil
+}
+
+// All implements Store.
+func (fs *FileStore) All() ([]string, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	ids := make([]string, 0, len(fs.sessions))
+	for id := range fs.sessions {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
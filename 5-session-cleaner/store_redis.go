@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrRedisNil is returned by RedisClient.Get when the key doesn't
+// exist, mirroring the sentinel (e.g. redis.Nil) that most Redis
+// client libraries use for the same thing.
+var ErrRedisNil = errors.New("redis: key does not exist")
+
+// RedisClient is the minimal subset of a Redis client RedisStore
+// needs. It's defined locally, rather than importing a specific
+// client library, so this exercise stays free of third-party
+// dependencies; adapt your client of choice (e.g.
+// github.com/redis/go-redis/v9) to this interface to use it here.
+type RedisClient interface {
+	// Get returns the value stored at key, or ErrRedisNil if key
+	// doesn't exist.
+	Get(ctx context.Context, key string) (string, error)
+	// Set stores value at key with the given expiry.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Del removes key. Deleting a key that doesn't exist is not an
+	// error.
+	Del(ctx context.Context, key string) error
+	// Scan returns every key matching the glob pattern match.
+	Scan(ctx context.Context, match string) ([]string, error)
+}
+
+// RedisStore is a Store backed by Redis. Each session is stored as a
+// JSON blob under its own key with a TTL, so expiry is handled by
+// Redis itself via `SET ... EX` rather than by our cleaner deleting
+// rows out of a local map.
+type RedisStore struct {
+	client RedisClient
+	prefix string
+	ttl    time.Duration
+}
+
+// NewRedisStore creates a RedisStore using client, storing keys under
+// prefix (defaulting to "session:") and expiring them after ttl of
+// inactivity.
+func NewRedisStore(client RedisClient, prefix string, ttl time.Duration) *RedisStore {
+	if prefix == "" {
+		prefix = "session:"
+	}
+	return &RedisStore{
+		client: client,
+		prefix: prefix,
+		ttl:    ttl,
+	}
+}
+
+// Find implements Store.
+func (s *RedisStore) Find(id string) (Session, bool, error) {
+	raw, err := s.client.Get(context.Background(), s.key(id))
+	if errors.Is(err, ErrRedisNil) {
+		return Session{}, false, nil
+	}
+	if err != nil {
+		return Session{}, false, err
+	}
+
+	var session Session
+	if err := json.Unmarshal([]byte(raw), &session); err != nil {
+		return Session{}, false, err
+	}
+	return session, true, nil
+}
+
+// Commit implements Store.
+func (s *RedisStore) Commit(id string, session Session) error {
+	raw, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(context.Background(), s.key(id), raw, s.ttl)
+}
+
+// Delete implements Store.
+func (s *RedisStore) Delete(id string) error {
+	return s.client.Del(context.Background(), s.key(id))
+}
+
+// All implements Store.
+func (s *RedisStore) All() ([]string, error) {
+	keys, err := s.client.Scan(context.Background(), s.prefix+"*")
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(keys))
+	for _, key := range keys {
+		ids = append(ids, strings.TrimPrefix(key, s.prefix))
+	}
+	return ids, nil
+}
+
+func (s *RedisStore) key(id string) string {
+	return s.prefix + id
+}
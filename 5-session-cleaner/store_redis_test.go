@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"path"
+	"sync"
+	"time"
+)
+
+// fakeRedisClient is an in-memory stand-in for a real Redis client,
+// implementing just enough of RedisClient to exercise RedisStore
+// without a network dependency.
+type fakeRedisClient struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func newFakeRedisClient() *fakeRedisClient {
+	return &fakeRedisClient{data: make(map[string][]byte)}
+}
+
+func (f *fakeRedisClient) Get(ctx context.Context, key string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	raw, ok := f.data[key]
+	if !ok {
+		return "", ErrRedisNil
+	}
+	return string(raw), nil
+}
+
+func (f *fakeRedisClient) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.data[key] = append([]byte(nil), value...)
+	return nil
+}
+
+func (f *fakeRedisClient) Del(ctx context.Context, key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.data, key)
+	return nil
+}
+
+func (f *fakeRedisClient) Scan(ctx context.Context, match string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var keys []string
+	for key := range f.data {
+		if ok, _ := path.Match(match, key); ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
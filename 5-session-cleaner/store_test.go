@@ -0,0 +1,137 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// storeSuite runs a common behavioral contract against any Store
+// implementation, so MemoryStore, FileStore and RedisStore are all
+// held to the same behavior.
+func storeSuite(t *testing.T, newStore func(t *testing.T) Store) {
+	t.Helper()
+
+	t.Run("FindMissing", func(t *testing.T) {
+		s := newStore(t)
+
+		_, ok, err := s.Find("missing")
+		if err != nil {
+			t.Fatalf("Find: %v", err)
+		}
+		if ok {
+			t.Fatalf("Find: got ok=true for a session that was never committed")
+		}
+	})
+
+	t.Run("CommitThenFind", func(t *testing.T) {
+		s := newStore(t)
+
+		want := Session{
+			Data:       map[string]interface{}{"website": "longhoang.de"},
+			LastUpdate: time.Now().Truncate(time.Second),
+		}
+		if err := s.Commit("a", want); err != nil {
+			t.Fatalf("Commit: %v", err)
+		}
+
+		got, ok, err := s.Find("a")
+		if err != nil {
+			t.Fatalf("Find: %v", err)
+		}
+		if !ok {
+			t.Fatalf("Find: got ok=false right after Commit")
+		}
+		if got.Data["website"] != want.Data["website"] {
+			t.Fatalf("Find: got Data %v, want %v", got.Data, want.Data)
+		}
+		if !got.LastUpdate.Equal(want.LastUpdate) {
+			t.Fatalf("Find: got LastUpdate %v, want %v", got.LastUpdate, want.LastUpdate)
+		}
+	})
+
+	t.Run("CommitOverwrites", func(t *testing.T) {
+		s := newStore(t)
+
+		if err := s.Commit("a", Session{Data: map[string]interface{}{"v": "first"}}); err != nil {
+			t.Fatalf("Commit: %v", err)
+		}
+		if err := s.Commit("a", Session{Data: map[string]interface{}{"v": "second"}}); err != nil {
+			t.Fatalf("Commit: %v", err)
+		}
+
+		got, ok, err := s.Find("a")
+		if err != nil || !ok {
+			t.Fatalf("Find: got ok=%v err=%v", ok, err)
+		}
+		if got.Data["v"] != "second" {
+			t.Fatalf("Commit: got Data[v]=%v, want the second Commit to win", got.Data["v"])
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		s := newStore(t)
+
+		if err := s.Commit("a", Session{Data: map[string]interface{}{}}); err != nil {
+			t.Fatalf("Commit: %v", err)
+		}
+		if err := s.Delete("a"); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, ok, err := s.Find("a"); err != nil || ok {
+			t.Fatalf("Find after Delete: got ok=%v err=%v, want ok=false", ok, err)
+		}
+
+		if err := s.Delete("never-existed"); err != nil {
+			t.Fatalf("Delete: deleting an unknown id should not error, got %v", err)
+		}
+	})
+
+	t.Run("All", func(t *testing.T) {
+		s := newStore(t)
+
+		if err := s.Commit("a", Session{Data: map[string]interface{}{}}); err != nil {
+			t.Fatalf("Commit: %v", err)
+		}
+		if err := s.Commit("b", Session{Data: map[string]interface{}{}}); err != nil {
+			t.Fatalf("Commit: %v", err)
+		}
+
+		ids, err := s.All()
+		if err != nil {
+			t.Fatalf("All: %v", err)
+		}
+
+		seen := make(map[string]bool, len(ids))
+		for _, id := range ids {
+			seen[id] = true
+		}
+		if !seen["a"] || !seen["b"] {
+			t.Fatalf("All: got %v, want both \"a\" and \"b\"", ids)
+		}
+	})
+}
+
+func TestMemoryStore(t *testing.T) {
+	storeSuite(t, func(t *testing.T) Store {
+		return NewMemoryStore()
+	})
+}
+
+func TestFileStore(t *testing.T) {
+	storeSuite(t, func(t *testing.T) Store {
+		path := filepath.Join(t.TempDir(), "sessions.json")
+		fs, err := NewFileStore(path, time.Hour)
+		if err != nil {
+			t.Fatalf("NewFileStore: %v", err)
+		}
+		t.Cleanup(func() { fs.Close() })
+		return fs
+	})
+}
+
+func TestRedisStore(t *testing.T) {
+	storeSuite(t, func(t *testing.T) Store {
+		return NewRedisStore(newFakeRedisClient(), "", time.Hour)
+	})
+}
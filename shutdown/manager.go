@@ -0,0 +1,155 @@
+// Package shutdown provides a reusable graceful-shutdown manager:
+// register components that need to clean up, then let a Manager
+// coordinate stopping them on SIGINT/SIGTERM with a grace deadline,
+// a hard-exit on a second signal, and an optional unconditional
+// timeout.
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Stoppable is a component that can be asked to stop gracefully. Stop
+// should return once the component has released its resources, or
+// once ctx is done, whichever comes first.
+type Stoppable interface {
+	Stop(ctx context.Context) error
+}
+
+// Option configures a Manager.
+type Option func(*Manager)
+
+// WithSignals overrides the set of signals that trigger shutdown. The
+// default is os.Interrupt (SIGINT) and SIGTERM.
+func WithSignals(signals ...os.Signal) Option {
+	return func(m *Manager) { m.signals = signals }
+}
+
+// WithTimeout triggers shutdown after d, measured from when Listen
+// starts waiting, even if no shutdown signal ever arrives. Shutdown
+// still gets the Manager's grace period to finish before the process
+// is force-exited.
+func WithTimeout(d time.Duration) Option {
+	return func(m *Manager) { m.timeout = d }
+}
+
+// osExit is os.Exit, indirected so tests can observe a hard-exit
+// without actually killing the test binary.
+var osExit = os.Exit
+
+// Manager coordinates a graceful shutdown across multiple components.
+// On the first shutdown signal it asks every registered Stoppable to
+// stop concurrently, giving them grace to do so; a second signal
+// force-exits the process immediately. Both this and the grace-deadline
+// hard-exit in Shutdown exit with status 0: they're an intended,
+// user-requested (or self-imposed) escalation of a clean shutdown, not
+// an error, so non-zero codes are reserved for genuine failure paths.
+type Manager struct {
+	signals []os.Signal
+	grace   time.Duration
+	timeout time.Duration
+
+	mu         sync.Mutex
+	components []Stoppable
+}
+
+// New creates a Manager that gives registered components grace to
+// finish stopping once shutdown begins. Use WithTimeout to also bound
+// the wait for a triggering signal.
+func New(grace time.Duration, opts ...Option) *Manager {
+	m := &Manager{
+		signals: []os.Signal{os.Interrupt, syscall.SIGTERM},
+		grace:   grace,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Register adds components to be stopped on shutdown.
+func (m *Manager) Register(components ...Stoppable) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.components = append(m.components, components...)
+}
+
+// Listen blocks until a shutdown signal arrives, or until the
+// WithTimeout deadline elapses if one was configured, then stops
+// every registered component and returns their aggregated errors. A
+// second signal received while components are stopping force-exits
+// the process immediately.
+func (m *Manager) Listen() error {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, m.signals...)
+	defer signal.Stop(sigChan)
+
+	var timeoutC <-chan time.Time
+	if m.timeout > 0 {
+		timer := time.NewTimer(m.timeout)
+		defer timer.Stop()
+		timeoutC = timer.C
+	}
+
+	select {
+	case <-sigChan:
+	case <-timeoutC:
+	}
+
+	// A second signal during shutdown is the last resort: kill the
+	// process right away instead of waiting for Stop to return.
+	go func() {
+		<-sigChan
+		osExit(0)
+	}()
+
+	return m.Shutdown()
+}
+
+// Shutdown stops every registered component concurrently, bounded by
+// the Manager's grace deadline, and returns their aggregated errors.
+// Unlike Listen, it doesn't wait for a signal, so callers can trigger
+// shutdown programmatically. If grace elapses before every component's
+// Stop has returned — for example because one ignores ctx and blocks
+// — Shutdown hard-exits the process via os.Exit rather than waiting
+// forever, since there's no safe way to abandon a Stop call that's
+// still running.
+func (m *Manager) Shutdown() error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.grace)
+	defer cancel()
+
+	m.mu.Lock()
+	components := append([]Stoppable(nil), m.components...)
+	m.mu.Unlock()
+
+	errs := make([]error, len(components))
+	var wg sync.WaitGroup
+	for i, c := range components {
+		wg.Add(1)
+		go func(i int, c Stoppable) {
+			defer wg.Done()
+			errs[i] = c.Stop(ctx)
+		}(i, c)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return errors.Join(errs...)
+	case <-ctx.Done():
+		osExit(0)
+		return nil // unreachable
+	}
+}
@@ -0,0 +1,85 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stopFunc adapts a plain function to Stoppable.
+type stopFunc func(ctx context.Context) error
+
+func (f stopFunc) Stop(ctx context.Context) error { return f(ctx) }
+
+// TestShutdownAggregatesConcurrentStopErrors checks that Shutdown runs
+// every component's Stop concurrently (rather than serially) and joins
+// all of their errors together, instead of stopping at the first one.
+func TestShutdownAggregatesConcurrentStopErrors(t *testing.T) {
+	m := New(time.Second)
+
+	errA := errors.New("component a failed")
+	errB := errors.New("component b failed")
+
+	var started int32
+	release := make(chan struct{})
+	stopWaiting := func(err error) stopFunc {
+		return func(ctx context.Context) error {
+			atomic.AddInt32(&started, 1)
+			<-release
+			return err
+		}
+	}
+	m.Register(stopWaiting(errA), stopWaiting(errB), stopWaiting(nil))
+
+	go func() {
+		deadline := time.Now().Add(time.Second)
+		for atomic.LoadInt32(&started) < 3 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		close(release)
+	}()
+
+	err := m.Shutdown()
+	if !errors.Is(err, errA) || !errors.Is(err, errB) {
+		t.Fatalf("Shutdown() error = %v, want it to join both component errors", err)
+	}
+}
+
+// TestShutdownHardExitsAfterGraceDeadline checks that Shutdown force-
+// exits, with status 0, once its grace deadline elapses without every
+// component's Stop returning — the case a component ignores ctx and
+// blocks, which would otherwise hang Shutdown forever.
+func TestShutdownHardExitsAfterGraceDeadline(t *testing.T) {
+	m := New(20 * time.Millisecond)
+
+	var exitCode int
+	exited := make(chan struct{})
+	prevExit := osExit
+	osExit = func(code int) {
+		exitCode = code
+		close(exited)
+		runtime.Goexit() // stand in for os.Exit halting the goroutine
+	}
+	t.Cleanup(func() { osExit = prevExit })
+
+	block := make(chan struct{})
+	t.Cleanup(func() { close(block) })
+	m.Register(stopFunc(func(ctx context.Context) error {
+		<-block // ignores ctx, simulating a component that won't stop
+		return nil
+	}))
+
+	go m.Shutdown()
+
+	select {
+	case <-exited:
+	case <-time.After(time.Second):
+		t.Fatal("Shutdown did not hard-exit after its grace deadline elapsed")
+	}
+	if exitCode != 0 {
+		t.Fatalf("osExit called with code %d, want 0 (an intended shutdown escalation, not an error)", exitCode)
+	}
+}